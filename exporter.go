@@ -0,0 +1,49 @@
+package traefik_datadog_tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Exporter sends a batch of finished spans to a tracing backend. Each
+// implementation is responsible for translating the neutral Span struct
+// into its backend's wire format.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []Span) error
+}
+
+// newExporter builds the Exporter selected by config.Backend, defaulting to
+// Datadog when unset for backwards compatibility.
+func newExporter(config *Config) (Exporter, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	backend := config.Backend
+	if backend == "" {
+		backend = "datadog"
+	}
+
+	switch backend {
+	case "datadog":
+		return &datadogExporter{client: client, agentURL: config.DatadogTracingAgentUrl}, nil
+	case "zipkin":
+		return &zipkinExporter{client: client, endpointURL: config.ZipkinUrl, serviceName: config.PluginName}, nil
+	case "jaeger":
+		return &jaegerExporter{client: client, collectorURL: config.JaegerUrl, serviceName: config.PluginName}, nil
+	case "otlp":
+		return &otlpExporter{client: client, endpointURL: config.OtlpUrl, headers: config.OtlpHeaders, serviceName: config.PluginName}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing backend %q", backend)
+	}
+}
+
+// checkResponseStatus returns an error when resp's status code is not 2xx,
+// so that a backend-side rejection is logged by spanWriter the same way a
+// transport failure would be instead of being silently treated as success.
+func checkResponseStatus(backend string, resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s exporter: backend returned %s", backend, resp.Status)
+	}
+	return nil
+}
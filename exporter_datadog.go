@@ -0,0 +1,68 @@
+package traefik_datadog_tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// datadogExporter sends spans to a Datadog agent's v0.4 traces endpoint.
+type datadogExporter struct {
+	client   *http.Client
+	agentURL string
+}
+
+// ExportSpans serializes spans as one trace per span and POSTs them in a
+// single request, matching Datadog's "array of traces" payload shape.
+func (e *datadogExporter) ExportSpans(ctx context.Context, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	traces := make([][]map[string]interface{}, len(spans))
+	for i, span := range spans {
+		traces[i] = []map[string]interface{}{spanToDatadogJSON(span)}
+	}
+
+	payload, err := json.Marshal(traces)
+	if err != nil {
+		return fmt.Errorf("serializing spans for datadog: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.agentURL+"/v0.4/traces", bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("building datadog request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans to datadog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponseStatus("datadog", resp)
+}
+
+// spanToDatadogJSON converts the neutral Span into the map shape expected by
+// the Datadog agent's v0.4 traces endpoint.
+func spanToDatadogJSON(span Span) map[string]interface{} {
+	errFlag := 0
+	if span.Error {
+		errFlag = 1
+	}
+	return map[string]interface{}{
+		"trace_id":  span.TraceIDLow,
+		"span_id":   span.SpanID,
+		"parent_id": span.ParentID,
+		"name":      span.Name,
+		"resource":  span.Resource,
+		"service":   span.Service,
+		"start":     span.Start.UnixNano(),
+		"duration":  span.Duration.Nanoseconds(),
+		"error":     errFlag,
+		"meta":      span.Meta,
+	}
+}
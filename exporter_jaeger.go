@@ -0,0 +1,92 @@
+package traefik_datadog_tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// jaegerExporter sends spans to a Jaeger collector's HTTP endpoint as a
+// Thrift-encoded Batch, per jaeger-idl's agent.thrift/jaeger.thrift.
+type jaegerExporter struct {
+	client       *http.Client
+	collectorURL string
+	serviceName  string
+}
+
+const (
+	jaegerTagTypeString   = 0
+	jaegerSpanFlagSampled = 1
+)
+
+// ExportSpans serializes spans as a single Thrift Batch and POSTs it to the
+// collector's jaeger.thrift HTTP endpoint.
+func (e *jaegerExporter) ExportSpans(ctx context.Context, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	var spanElems bytes.Buffer
+	for _, span := range spans {
+		spanElems.Write(encodeJaegerSpan(span))
+	}
+
+	var process thriftWriter
+	process.writeString(1, e.serviceName)
+	process.stop()
+
+	var batch thriftWriter
+	batch.writeStruct(1, process.bytes())
+	batch.writeList(2, thriftTypeStruct, len(spans), spanElems.Bytes())
+	batch.stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.collectorURL+"/api/traces?format=jaeger.thrift", bytes.NewReader(batch.bytes()))
+	if err != nil {
+		return fmt.Errorf("building jaeger request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-thrift")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans to jaeger: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponseStatus("jaeger", resp)
+}
+
+// encodeJaegerSpan encodes a single Span as a Thrift Span struct.
+func encodeJaegerSpan(span Span) []byte {
+	var w thriftWriter
+	w.writeI64(1, int64(span.TraceIDLow))
+	w.writeI64(2, int64(span.TraceIDHigh))
+	w.writeI64(3, int64(span.SpanID))
+	w.writeI64(4, int64(span.ParentID))
+	w.writeString(5, span.Name)
+	w.writeI32(7, jaegerSpanFlagSampled)
+	w.writeI64(8, span.Start.UnixMicro())
+	w.writeI64(9, span.Duration.Microseconds())
+
+	if len(span.Meta) > 0 {
+		var tagElems bytes.Buffer
+		for key, value := range span.Meta {
+			tagElems.Write(encodeJaegerStringTag(key, value))
+		}
+		w.writeList(10, thriftTypeStruct, len(span.Meta), tagElems.Bytes())
+	}
+
+	w.stop()
+	return w.bytes()
+}
+
+// encodeJaegerStringTag encodes a single key/value pair as a Thrift Tag
+// struct of type string.
+func encodeJaegerStringTag(key, value string) []byte {
+	var w thriftWriter
+	w.writeString(1, key)
+	w.writeI32(2, jaegerTagTypeString)
+	w.writeString(3, value)
+	w.stop()
+	return w.bytes()
+}
@@ -0,0 +1,132 @@
+package traefik_datadog_tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// otlpExporter sends spans to an OTLP/HTTP collector as a protobuf-encoded
+// ExportTraceServiceRequest, per opentelemetry-proto's trace_service.proto
+// and trace.proto.
+type otlpExporter struct {
+	client      *http.Client
+	endpointURL string
+	headers     map[string]string
+	serviceName string
+}
+
+// OTLP status codes, per trace.proto's Status.StatusCode.
+const (
+	otlpStatusCodeUnset = 0
+	otlpStatusCodeError = 2
+)
+
+// ExportSpans serializes spans as a single ResourceSpans/ScopeSpans and
+// POSTs the protobuf-encoded request to the collector's traces endpoint.
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	var scopeSpans []byte
+	for _, span := range spans {
+		scopeSpans = appendBytesField(scopeSpans, 2, encodeOTLPSpan(span))
+	}
+	scopeSpansMsg := appendBytesField(nil, 1, encodeOTLPInstrumentationScope())
+	scopeSpansMsg = append(scopeSpansMsg, scopeSpans...)
+
+	resourceMsg := appendBytesField(nil, 1, encodeOTLPStringAttribute("service.name", e.serviceName))
+
+	var resourceSpansMsg []byte
+	resourceSpansMsg = appendBytesField(resourceSpansMsg, 1, resourceMsg)
+	resourceSpansMsg = appendBytesField(resourceSpansMsg, 2, scopeSpansMsg)
+
+	request := appendBytesField(nil, 1, resourceSpansMsg)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpointURL+"/v1/traces", bytes.NewReader(request))
+	if err != nil {
+		return fmt.Errorf("building otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans to otlp collector: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponseStatus("otlp", resp)
+}
+
+// encodeOTLPInstrumentationScope encodes the InstrumentationScope message
+// identifying this plugin as the span producer.
+func encodeOTLPInstrumentationScope() []byte {
+	var msg []byte
+	msg = appendStringField(msg, 1, "traefik-datadog-tracing")
+	return msg
+}
+
+// encodeOTLPSpan encodes a single Span message. OTLP trace/span IDs are
+// fixed-width big-endian byte strings rather than the decimal/hex text used
+// by the other backends.
+func encodeOTLPSpan(span Span) []byte {
+	var traceID [16]byte
+	putUint64BE(traceID[0:8], span.TraceIDHigh)
+	putUint64BE(traceID[8:16], span.TraceIDLow)
+
+	var spanID [8]byte
+	putUint64BE(spanID[:], span.SpanID)
+
+	var msg []byte
+	msg = appendBytesField(msg, 1, traceID[:])
+	msg = appendBytesField(msg, 2, spanID[:])
+	if span.ParentID != 0 {
+		var parentID [8]byte
+		putUint64BE(parentID[:], span.ParentID)
+		msg = appendBytesField(msg, 4, parentID[:])
+	}
+	msg = appendStringField(msg, 5, span.Name)
+	msg = appendVarintField(msg, 6, 3) // SPAN_KIND_CLIENT
+	msg = appendFixed64Field(msg, 7, uint64(span.Start.UnixNano()))
+	msg = appendFixed64Field(msg, 8, uint64(span.Start.Add(span.Duration).UnixNano()))
+
+	for key, value := range span.Meta {
+		msg = appendBytesField(msg, 9, encodeOTLPStringAttribute(key, value))
+	}
+
+	statusCode := otlpStatusCodeUnset
+	if span.Error {
+		statusCode = otlpStatusCodeError
+	}
+	msg = appendBytesField(msg, 15, encodeOTLPStatus(statusCode))
+
+	return msg
+}
+
+// encodeOTLPStringAttribute encodes a KeyValue message whose value is a
+// string AnyValue.
+func encodeOTLPStringAttribute(key, value string) []byte {
+	anyValue := appendStringField(nil, 1, value)
+
+	var kv []byte
+	kv = appendStringField(kv, 1, key)
+	kv = appendBytesField(kv, 2, anyValue)
+	return kv
+}
+
+// encodeOTLPStatus encodes a Status message with the given status code.
+func encodeOTLPStatus(code int) []byte {
+	return appendVarintField(nil, 3, uint64(code))
+}
+
+func putUint64BE(dst []byte, v uint64) {
+	for i := 0; i < 8; i++ {
+		dst[7-i] = byte(v)
+		v >>= 8
+	}
+}
@@ -0,0 +1,342 @@
+package traefik_datadog_tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testSpan() Span {
+	return Span{
+		TraceIDHigh: 0xa,
+		TraceIDLow:  0xb,
+		SpanID:      0xc,
+		ParentID:    0xd,
+		Name:        "op",
+		Resource:    "op",
+		Service:     "svc",
+		Start:       time.Unix(1700000000, 0),
+		Duration:    5 * time.Millisecond,
+		Meta:        map[string]string{"http.method": "GET"},
+	}
+}
+
+func TestCheckResponseStatusOK(t *testing.T) {
+	resp := &http.Response{StatusCode: 200, Status: "200 OK"}
+	if err := checkResponseStatus("test", resp); err != nil {
+		t.Fatalf("unexpected error for a 2xx response: %v", err)
+	}
+}
+
+func TestCheckResponseStatusError(t *testing.T) {
+	resp := &http.Response{StatusCode: 500, Status: "500 Internal Server Error"}
+	if err := checkResponseStatus("test", resp); err == nil {
+		t.Fatalf("expected an error for a non-2xx response")
+	}
+}
+
+func TestNewExporterUnknownBackend(t *testing.T) {
+	if _, err := newExporter(&Config{Backend: "made-up"}); err == nil {
+		t.Fatalf("expected an error for an unknown backend")
+	}
+}
+
+func TestDatadogExporterPayload(t *testing.T) {
+	var captured []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, r.ContentLength)
+		r.Body.Read(buf)
+		captured = buf
+		if r.URL.Path != "/v0.4/traces" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	e := &datadogExporter{client: server.Client(), agentURL: server.URL}
+	if err := e.ExportSpans(context.Background(), []Span{testSpan()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var traces [][]map[string]interface{}
+	if err := json.Unmarshal(captured, &traces); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if len(traces) != 1 || len(traces[0]) != 1 {
+		t.Fatalf("expected one trace with one span, got %+v", traces)
+	}
+	span := traces[0][0]
+	if span["span_id"].(float64) != 0xc {
+		t.Fatalf("unexpected span_id: %v", span["span_id"])
+	}
+	if span["name"].(string) != "op" {
+		t.Fatalf("unexpected name: %v", span["name"])
+	}
+}
+
+func TestZipkinExporterPayload(t *testing.T) {
+	var captured []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, r.ContentLength)
+		r.Body.Read(buf)
+		captured = buf
+		if r.URL.Path != "/api/v2/spans" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	e := &zipkinExporter{client: server.Client(), endpointURL: server.URL, serviceName: "svc"}
+	if err := e.ExportSpans(context.Background(), []Span{testSpan()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var spans []zipkinSpan
+	if err := json.Unmarshal(captured, &spans); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(spans))
+	}
+	if spans[0].TraceID != "000000000000000a000000000000000b" {
+		t.Fatalf("unexpected trace id: %s", spans[0].TraceID)
+	}
+	if spans[0].ParentID != "000000000000000d" {
+		t.Fatalf("unexpected parent id: %s", spans[0].ParentID)
+	}
+}
+
+func TestJaegerExporterPayload(t *testing.T) {
+	var captured []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, r.ContentLength)
+		r.Body.Read(buf)
+		captured = buf
+	}))
+	defer server.Close()
+
+	e := &jaegerExporter{client: server.Client(), collectorURL: server.URL, serviceName: "svc"}
+	if err := e.ExportSpans(context.Background(), []Span{testSpan()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	batch := decodeThriftStruct(captured)
+	spansField, ok := batch[2]
+	if !ok || spansField.typ != thriftTypeList {
+		t.Fatalf("expected field 2 to be the spans list, got %+v", batch)
+	}
+	elems := decodeThriftList(spansField.raw)
+	if len(elems) != 1 {
+		t.Fatalf("expected one encoded span, got %d", len(elems))
+	}
+
+	span := decodeThriftStruct(elems[0])
+	tagsField, ok := span[10]
+	if !ok || tagsField.typ != thriftTypeList {
+		t.Fatalf("expected field 10 to be the tags list, got %+v", span)
+	}
+	tagElems := decodeThriftList(tagsField.raw)
+	if len(tagElems) != 1 {
+		t.Fatalf("expected one tag, got %d", len(tagElems))
+	}
+
+	tag := decodeThriftStruct(tagElems[0])
+	vStr, ok := tag[3]
+	if !ok || vStr.typ != thriftTypeString {
+		t.Fatalf("expected the tag's string value at field id 3 (vStr), got fields %+v", tag)
+	}
+	if string(vStr.raw[4:]) != "GET" {
+		t.Fatalf("unexpected tag value: %q", vStr.raw[4:])
+	}
+}
+
+func TestOTLPExporterPayload(t *testing.T) {
+	var captured []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, r.ContentLength)
+		r.Body.Read(buf)
+		captured = buf
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	e := &otlpExporter{client: server.Client(), endpointURL: server.URL, serviceName: "svc"}
+	if err := e.ExportSpans(context.Background(), []Span{testSpan()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	request := decodeProtoFields(captured)
+	resourceSpans := findProtoField(t, request, 1)
+	resourceSpansMsg := decodeProtoFields(resourceSpans.bytes)
+
+	scopeSpans := findProtoField(t, resourceSpansMsg, 2)
+	scopeSpansMsg := decodeProtoFields(scopeSpans.bytes)
+
+	spanField := findProtoField(t, scopeSpansMsg, 2)
+	spanMsg := decodeProtoFields(spanField.bytes)
+
+	traceID := findProtoField(t, spanMsg, 1)
+	if binary.BigEndian.Uint64(traceID.bytes[8:]) != 0xb {
+		t.Fatalf("unexpected trace id low bits: %x", traceID.bytes)
+	}
+
+	name := findProtoField(t, spanMsg, 5)
+	if string(name.bytes) != "op" {
+		t.Fatalf("unexpected span name: %q", name.bytes)
+	}
+}
+
+// --- Thrift decoding helpers, used only to assert the wire shape of the
+// jaeger exporter's hand-rolled encoder in tests. ---
+
+type thriftField struct {
+	typ byte
+	raw []byte
+}
+
+// decodeThriftStruct parses a Thrift TBinaryProtocol struct body (as written
+// by thriftWriter) into its fields, keyed by field id.
+func decodeThriftStruct(buf []byte) map[int16]thriftField {
+	fields := map[int16]thriftField{}
+	for len(buf) > 0 {
+		typ := buf[0]
+		if typ == thriftTypeStop {
+			break
+		}
+		id := int16(binary.BigEndian.Uint16(buf[1:3]))
+		buf = buf[3:]
+
+		var raw []byte
+		raw, buf = decodeThriftValue(typ, buf)
+		fields[id] = thriftField{typ: typ, raw: raw}
+	}
+	return fields
+}
+
+// decodeThriftValue consumes one value of the given type from buf and
+// returns the raw bytes of that value along with the remaining buffer.
+func decodeThriftValue(typ byte, buf []byte) (raw []byte, rest []byte) {
+	switch typ {
+	case thriftTypeBool:
+		return buf[:1], buf[1:]
+	case thriftTypeDouble, thriftTypeI64:
+		return buf[:8], buf[8:]
+	case thriftTypeI32:
+		return buf[:4], buf[4:]
+	case thriftTypeString:
+		n := binary.BigEndian.Uint32(buf[:4])
+		return buf[: 4+n : 4+n], buf[4+n:]
+	case thriftTypeStruct:
+		n := structLen(buf)
+		return buf[:n], buf[n:]
+	case thriftTypeList:
+		elemType := buf[0]
+		count := binary.BigEndian.Uint32(buf[1:5])
+		n := uint32(5)
+		for i := uint32(0); i < count; i++ {
+			_, tail := decodeThriftValue(elemType, buf[n:])
+			n = uint32(len(buf)) - uint32(len(tail))
+		}
+		return buf[:n], buf[n:]
+	default:
+		panic("unsupported thrift type in test decoder")
+	}
+}
+
+// structLen returns the length, including the trailing STOP byte, of the
+// struct encoded at the start of buf.
+func structLen(buf []byte) int {
+	i := 0
+	for {
+		typ := buf[i]
+		if typ == thriftTypeStop {
+			return i + 1
+		}
+		i += 3 // field header
+		_, rest := decodeThriftValue(typ, buf[i:])
+		i = len(buf) - len(rest)
+	}
+}
+
+// decodeThriftList splits an already-unwrapped list value (as produced by
+// decodeThriftValue for thriftTypeList) into its individual elements.
+func decodeThriftList(buf []byte) [][]byte {
+	elemType := buf[0]
+	count := binary.BigEndian.Uint32(buf[1:5])
+	buf = buf[5:]
+
+	elems := make([][]byte, 0, count)
+	for i := uint32(0); i < count; i++ {
+		raw, rest := decodeThriftValue(elemType, buf)
+		elems = append(elems, raw)
+		buf = rest
+	}
+	return elems
+}
+
+// --- Protobuf decoding helpers, used only to assert the wire shape of the
+// otlp exporter's hand-rolled encoder in tests. ---
+
+type protoField struct {
+	num      int
+	wireType int
+	bytes    []byte
+}
+
+func decodeProtoFields(buf []byte) []protoField {
+	var fields []protoField
+	for len(buf) > 0 {
+		tag, n := decodeVarint(buf)
+		buf = buf[n:]
+		field := protoField{num: int(tag >> 3), wireType: int(tag & 0x7)}
+
+		switch field.wireType {
+		case protoWireVarint:
+			_, n := decodeVarint(buf)
+			field.bytes = buf[:n]
+			buf = buf[n:]
+		case protoWireFixed64:
+			field.bytes = buf[:8]
+			buf = buf[8:]
+		case protoWireBytes:
+			length, n := decodeVarint(buf)
+			buf = buf[n:]
+			field.bytes = buf[:length]
+			buf = buf[length:]
+		default:
+			panic("unsupported wire type in test decoder")
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func decodeVarint(buf []byte) (uint64, int) {
+	var v uint64
+	var shift uint
+	for i, b := range buf {
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, i + 1
+		}
+		shift += 7
+	}
+	return v, len(buf)
+}
+
+func findProtoField(t *testing.T, fields []protoField, num int) protoField {
+	t.Helper()
+	for _, f := range fields {
+		if f.num == num {
+			return f
+		}
+	}
+	t.Fatalf("field %d not found in %+v", num, fields)
+	return protoField{}
+}
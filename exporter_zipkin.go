@@ -0,0 +1,88 @@
+package traefik_datadog_tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// zipkinExporter sends spans to a Zipkin-compatible collector's v2 JSON
+// endpoint.
+type zipkinExporter struct {
+	client      *http.Client
+	endpointURL string
+	serviceName string
+}
+
+// zipkinSpan mirrors the fields Zipkin's v2 API accepts, per
+// https://zipkin.io/zipkin-api/#/default/post_spans.
+type zipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind,omitempty"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint zipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// ExportSpans POSTs spans as a single Zipkin v2 JSON array.
+func (e *zipkinExporter) ExportSpans(ctx context.Context, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	zspans := make([]zipkinSpan, len(spans))
+	for i, span := range spans {
+		zspans[i] = zipkinSpan{
+			TraceID:       zipkinTraceID(span.TraceIDHigh, span.TraceIDLow),
+			ID:            fmt.Sprintf("%016x", span.SpanID),
+			Name:          span.Name,
+			Kind:          "CLIENT",
+			Timestamp:     span.Start.UnixMicro(),
+			Duration:      span.Duration.Microseconds(),
+			LocalEndpoint: zipkinEndpoint{ServiceName: e.serviceName},
+			Tags:          span.Meta,
+		}
+		if span.ParentID != 0 {
+			zspans[i].ParentID = fmt.Sprintf("%016x", span.ParentID)
+		}
+	}
+
+	payload, err := json.Marshal(zspans)
+	if err != nil {
+		return fmt.Errorf("serializing spans for zipkin: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpointURL+"/api/v2/spans", bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("building zipkin request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans to zipkin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponseStatus("zipkin", resp)
+}
+
+// zipkinTraceID hex-encodes the trace ID, using the 64-bit form when the
+// high bits are unset (as for traces continued from a 64-bit upstream ID) so
+// that those keep the shorter, traditional 16-character ID.
+func zipkinTraceID(high, low uint64) string {
+	if high == 0 {
+		return fmt.Sprintf("%016x", low)
+	}
+	return fmt.Sprintf("%016x%016x", high, low)
+}
@@ -1,9 +1,7 @@
 package traefik_datadog_tracing
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -16,6 +14,18 @@ type Config struct {
 	GlobalTags             map[string]string `json:"globalTags,omitempty"`             // Global tags for all spans
 	PluginName             string            `json:"pluginName,omitempty"`             // Name used for service and operations
 	DatadogTracingAgentUrl string            `json:"datadogTracingAgentUrl,omitempty"` // Datadog agent URL
+	Propagators            []string          `json:"propagators,omitempty"`            // Trace context formats to read/write: "datadog", "tracecontext", "b3"
+	MaxQueueSize           int               `json:"maxQueueSize,omitempty"`           // Max spans buffered before new spans are dropped
+	FlushInterval          string            `json:"flushInterval,omitempty"`          // How often to flush buffered spans, e.g. "2s"
+	FlushBatchSize         int               `json:"flushBatchSize,omitempty"`         // Flush as soon as this many spans are buffered
+	Backend                string            `json:"backend,omitempty"`                // Tracing backend: "datadog", "zipkin", "jaeger", or "otlp"
+	ZipkinUrl              string            `json:"zipkinUrl,omitempty"`              // Zipkin collector base URL, used when Backend is "zipkin"
+	JaegerUrl              string            `json:"jaegerUrl,omitempty"`              // Jaeger collector base URL, used when Backend is "jaeger"
+	OtlpUrl                string            `json:"otlpUrl,omitempty"`                // OTLP/HTTP collector base URL, used when Backend is "otlp"
+	OtlpHeaders            map[string]string `json:"otlpHeaders,omitempty"`            // Extra headers sent with every OTLP export request
+	SamplingRate           float64           `json:"samplingRate,omitempty"`           // Fraction of traces to keep, between 0 and 1 (default: always on)
+	MaxTracesPerSecond     float64           `json:"maxTracesPerSecond,omitempty"`     // Rate-limit sampling instead of a fixed fraction; takes priority over SamplingRate
+	SamplerOverrides       []SamplerOverride `json:"samplerOverrides,omitempty"`       // Per-route sampling overrides, matched in order
 }
 
 // CreateConfig initializes the default plugin configuration
@@ -24,31 +34,101 @@ func CreateConfig() *Config {
 		GlobalTags:             map[string]string{},
 		DatadogTracingAgentUrl: "http://localhost:8126",
 		PluginName:             "tracingplugin",
+		Propagators:            []string{"datadog", "tracecontext", "b3"},
+		MaxQueueSize:           1000,
+		FlushInterval:          "2s",
+		FlushBatchSize:         100,
+		Backend:                "datadog",
+		SamplingRate:           1,
 	}
 }
 
 // TracingPlugin defines the plugin structure
 type TracingPlugin struct {
-	next                   http.Handler
-	name                   string
-	globalTags             map[string]string
-	datadogTracingAgentUrl string
-	pluginName             string
+	next           http.Handler
+	name           string
+	globalTags     map[string]string
+	pluginName     string
+	propagators    []string
+	idGen          *idGenerator
+	writer         *spanWriter
+	defaultSampler Sampler
+	routeSamplers  []routeSampler
 }
 
 // New creates a new plugin instance
 func New(ctx context.Context, next http.Handler, config *Config, name string) (http.Handler, error) {
 	log.Printf("Initializing plugin: name=%s, globalTags=%v, datadogTracingAgentUrl=%s", name, config.GlobalTags, config.DatadogTracingAgentUrl)
 
+	propagators := config.Propagators
+	if len(propagators) == 0 {
+		propagators = []string{"datadog"}
+	}
+
+	maxQueueSize := config.MaxQueueSize
+	if maxQueueSize <= 0 {
+		maxQueueSize = 1000
+	}
+	flushBatchSize := config.FlushBatchSize
+	if flushBatchSize <= 0 {
+		flushBatchSize = 100
+	}
+	flushInterval, err := time.ParseDuration(config.FlushInterval)
+	if err != nil {
+		flushInterval = 2 * time.Second
+	}
+
+	exporter, err := newExporter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := newSpanWriter(exporter, maxQueueSize, flushBatchSize, flushInterval)
+	writer.start()
+
+	// Traefik's plugin contract has no explicit teardown hook, but it does
+	// cancel the context passed into New when the middleware instance is
+	// torn down (e.g. on a dynamic configuration reload), so that's the
+	// only signal we actually get to flush what's left in the queue.
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := writer.shutdown(shutdownCtx); err != nil {
+			log.Printf("spanWriter: error flushing spans during shutdown: %v", err)
+		}
+	}()
+
+	routeSamplers := make([]routeSampler, len(config.SamplerOverrides))
+	for i, override := range config.SamplerOverrides {
+		routeSamplers[i] = routeSampler{
+			pathPrefix: override.PathPrefix,
+			host:       override.Host,
+			sampler:    newSampler(override.SamplingRate, override.MaxTracesPerSecond),
+		}
+	}
+
 	return &TracingPlugin{
-		next:                   next,
-		name:                   name,
-		globalTags:             config.GlobalTags,
-		datadogTracingAgentUrl: config.DatadogTracingAgentUrl,
-		pluginName:             config.PluginName,
+		next:           next,
+		name:           name,
+		globalTags:     config.GlobalTags,
+		pluginName:     config.PluginName,
+		propagators:    propagators,
+		idGen:          newIDGenerator(),
+		writer:         writer,
+		defaultSampler: newSampler(config.SamplingRate, config.MaxTracesPerSecond),
+		routeSamplers:  routeSamplers,
 	}, nil
 }
 
+// Shutdown flushes any spans still buffered in the writer and stops its
+// background goroutine. New already wires this up to the teardown of its
+// ctx argument; this method exists for callers (tests, or embedders that
+// manage the plugin's lifecycle directly) that need to trigger it manually.
+func (p *TracingPlugin) Shutdown(ctx context.Context) error {
+	return p.writer.shutdown(ctx)
+}
+
 // TimingContext wraps a parent context and adds timing functionality
 type TimingContext struct {
 	parent    context.Context
@@ -99,29 +179,54 @@ func (p *TracingPlugin) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	timingCtx := NewTimingContext(req.Context())
 	req = req.WithContext(timingCtx)
 
-	// Create a new trace and span
-	traceID, spanID := createTraceAndSpan()
+	// Continue an existing trace if the request already carries one,
+	// otherwise start a new one.
+	sc, extracted := p.extractTraceHeaders(req)
+	if extracted {
+		sc = p.childSpanContext(sc)
+	} else {
+		sc = p.newSpanContext()
+		sc.Priority = p.samplerFor(req).Sample(sc.TraceIDLow)
+	}
 
 	// Inject distributed tracing headers into the request
-	injectTraceHeaders(req, traceID, spanID)
+	p.injectTraceHeaders(req, sc)
+
+	// Wrap the ResponseWriter so we can observe the status code and response
+	// size the downstream handler produces.
+	recorder := newStatusRecorder(rw)
 
 	// Pass the request to the next handler
-	p.next.ServeHTTP(rw, req)
+	p.next.ServeHTTP(recorder, req)
 
 	// Calculate the duration after the middleware has completed
-	if tc, ok := req.Context().(*TimingContext); ok {
+	if tc, ok := req.Context().(*TimingContext); ok && sc.Priority.Keep() {
 		duration := tc.Duration()
 
 		// Extract span attributes, including origin IP
-		spanAttributes := p.extractSpanAttributes(req)
-
-		// Send the span to Datadog
-		p.sendCustomSpanWithDuration(traceID, spanID, duration, spanAttributes)
+		spanAttributes := p.extractSpanAttributes(req, recorder)
+
+		// Hand the finished span off to the background writer instead of
+		// blocking the request on a synchronous call to Datadog.
+		p.writer.enqueue(Span{
+			TraceIDHigh: sc.TraceIDHigh,
+			TraceIDLow:  sc.TraceIDLow,
+			SpanID:      sc.SpanID,
+			ParentID:    sc.ParentID,
+			Name:        p.pluginName + "-operation",
+			Resource:    p.pluginName + "-operation",
+			Service:     p.pluginName + "-service",
+			Start:       time.Now().Add(-duration),
+			Duration:    duration,
+			Meta:        spanAttributes,
+			Error:       recorder.statusCode >= http.StatusInternalServerError,
+		})
 	}
 }
 
-// extractSpanAttributes extracts attributes from the request and global tags
-func (p *TracingPlugin) extractSpanAttributes(req *http.Request) map[string]string {
+// extractSpanAttributes extracts attributes from the request, the response
+// the downstream handler produced, and global tags.
+func (p *TracingPlugin) extractSpanAttributes(req *http.Request, rec *statusRecorder) map[string]string {
 	attributes := map[string]string{}
 
 	// Add global tags
@@ -133,6 +238,17 @@ func (p *TracingPlugin) extractSpanAttributes(req *http.Request) map[string]stri
 	attributes["http.method"] = req.Method
 	attributes["http.url"] = req.URL.Path
 	attributes["http.host"] = req.Host
+	attributes["http.scheme"] = requestScheme(req)
+	attributes["http.user_agent"] = req.Header.Get("User-Agent")
+	attributes["http.request_content_length"] = strconv.FormatInt(req.ContentLength, 10)
+
+	// Extract response attributes captured by the statusRecorder
+	attributes["http.status_code"] = strconv.Itoa(rec.statusCode)
+	attributes["http.response_content_length"] = strconv.FormatInt(rec.bytesWritten, 10)
+	if rec.statusCode >= http.StatusInternalServerError {
+		attributes["error"] = "true"
+		attributes["error.type"] = "http_5xx"
+	}
 
 	// Extract the origin IP from X-Forwarded-For or X-Real-Ip
 	attributes["origin_ip"] = p.extractOriginIP(req)
@@ -144,6 +260,15 @@ func (p *TracingPlugin) extractSpanAttributes(req *http.Request) map[string]stri
 	return attributes
 }
 
+// requestScheme reports "https" or "http", using req.TLS as the source of
+// truth since req.URL.Scheme is typically empty for server-side requests.
+func requestScheme(req *http.Request) string {
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
 // extractOriginIP checks for X-Forwarded-For and falls back to X-Real-Ip
 func (p *TracingPlugin) extractOriginIP(req *http.Request) string {
 	xForwardedFor := req.Header.Get("X-Forwarded-For")
@@ -158,55 +283,3 @@ func (p *TracingPlugin) extractOriginIP(req *http.Request) string {
 	// Fallback to X-Real-Ip if X-Forwarded-For is missing
 	return req.Header.Get("X-Real-Ip")
 }
-
-// createTraceAndSpan generates unique IDs for trace and span
-func createTraceAndSpan() (uint64, uint64) {
-	traceID := uint64(time.Now().UnixNano()) // Example trace ID
-	spanID := traceID + 1                    // Example span ID
-	return traceID, spanID
-}
-
-// injectTraceHeaders adds tracing headers to the request
-func injectTraceHeaders(req *http.Request, traceID, spanID uint64) {
-	req.Header.Set("x-datadog-trace-id", strconv.FormatUint(traceID, 10))
-	req.Header.Set("x-datadog-parent-id", strconv.FormatUint(spanID, 10))
-	req.Header.Set("x-datadog-sampling-priority", "1") // Sampling priority
-}
-
-// sendCustomSpanWithDuration sends a custom span directly to Datadog
-func (p *TracingPlugin) sendCustomSpanWithDuration(traceID, spanID uint64, duration time.Duration, meta map[string]string) {
-	span := map[string]interface{}{
-		"trace_id":  traceID,
-		"span_id":   spanID,
-		"parent_id": 0,
-		"name":      p.pluginName + "-operation",
-		"resource":  p.pluginName + "-operation",
-		"service":   p.pluginName + "-service",
-		"start":     time.Now().Add(-duration).UnixNano(),
-		"duration":  duration.Nanoseconds(),
-		"meta":      meta,
-	}
-
-	payload, err := json.Marshal(span)
-	if err != nil {
-		log.Printf("Error serializing span: %v", err)
-		return
-	}
-
-	trace := [][]map[string]interface{}{
-		{span},
-	}
-
-	payload, err = json.Marshal(trace)
-	if err != nil {
-		log.Printf("Error serializing trace: %v", err)
-		return
-	}
-
-	resp, err := http.Post(p.datadogTracingAgentUrl+"/v0.4/traces", "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		log.Printf("Error sending trace to Datadog: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-}
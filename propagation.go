@@ -0,0 +1,279 @@
+package traefik_datadog_tracing
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext carries the trace/span identity for a single request, whether
+// it was freshly generated or extracted from an incoming propagation header.
+type SpanContext struct {
+	TraceIDHigh uint64 // high 64 bits of a 128-bit trace ID (0 for 64-bit traces)
+	TraceIDLow  uint64 // low 64 bits of the trace ID; used alone for Datadog's 64-bit ID
+	SpanID      uint64
+	ParentID    uint64
+	Priority    SamplingPriority
+}
+
+// idGenerator produces random, independent trace and span IDs. It mirrors
+// the pattern used by Jaeger's client: a single crypto-seeded PRNG shared by
+// the tracer and guarded by a mutex, since math/rand's generators are not
+// safe for concurrent use.
+type idGenerator struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newIDGenerator creates an idGenerator seeded from a cryptographic source
+// so that IDs are not predictable or prone to colliding across instances.
+func newIDGenerator() *idGenerator {
+	var seed [8]byte
+	if _, err := cryptorand.Read(seed[:]); err != nil {
+		// Fall back to a time-derived seed; this should not happen in practice.
+		binary.LittleEndian.PutUint64(seed[:], uint64(time.Now().UnixNano()))
+	}
+	return &idGenerator{rnd: rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))}
+}
+
+// next63 returns a random, non-zero uint64 suitable for use as a trace or
+// span ID.
+func (g *idGenerator) next63() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := g.rnd.Uint64()
+	for id == 0 {
+		id = g.rnd.Uint64()
+	}
+	return id
+}
+
+// newSpanContext starts a brand new trace with a freshly generated 128-bit
+// trace ID and a root span. The sampling decision is left to the caller,
+// since only the root span's creator runs the sampler.
+func (p *TracingPlugin) newSpanContext() SpanContext {
+	return SpanContext{
+		TraceIDHigh: p.idGen.next63(),
+		TraceIDLow:  p.idGen.next63(),
+		SpanID:      p.idGen.next63(),
+	}
+}
+
+// childSpanContext continues an extracted trace with a new span ID,
+// inheriting the parent's sampling decision so every span in a trace agrees
+// on whether it was kept.
+func (p *TracingPlugin) childSpanContext(parent SpanContext) SpanContext {
+	return SpanContext{
+		TraceIDHigh: parent.TraceIDHigh,
+		TraceIDLow:  parent.TraceIDLow,
+		SpanID:      p.idGen.next63(),
+		ParentID:    parent.SpanID,
+		Priority:    parent.Priority,
+	}
+}
+
+// extractTraceHeaders attempts to parse an incoming trace context from the
+// request, trying each configured propagator in order and returning the
+// first successful match. The second return value reports whether a trace
+// context was found at all.
+func (p *TracingPlugin) extractTraceHeaders(req *http.Request) (SpanContext, bool) {
+	for _, name := range p.propagators {
+		switch name {
+		case "tracecontext":
+			if sc, ok := extractW3CTraceContext(req); ok {
+				return sc, true
+			}
+		case "b3":
+			if sc, ok := extractB3(req); ok {
+				return sc, true
+			}
+		case "datadog":
+			if sc, ok := extractDatadog(req); ok {
+				return sc, true
+			}
+		}
+	}
+	return SpanContext{}, false
+}
+
+// injectTraceHeaders writes the span context onto the outgoing request in
+// every configured propagation format so downstream services can pick
+// whichever one they understand.
+func (p *TracingPlugin) injectTraceHeaders(req *http.Request, sc SpanContext) {
+	priority := strconv.Itoa(int(sc.Priority))
+
+	for _, name := range p.propagators {
+		switch name {
+		case "datadog":
+			req.Header.Set("x-datadog-trace-id", strconv.FormatUint(sc.TraceIDLow, 10))
+			req.Header.Set("x-datadog-parent-id", strconv.FormatUint(sc.SpanID, 10))
+			req.Header.Set("x-datadog-sampling-priority", priority)
+			if sc.TraceIDHigh != 0 {
+				req.Header.Set("x-datadog-tags", fmt.Sprintf("_dd.p.tid=%016x", sc.TraceIDHigh))
+			}
+		case "tracecontext":
+			flags := "00"
+			if sc.Priority.Keep() {
+				flags = "01"
+			}
+			req.Header.Set("traceparent", fmt.Sprintf("00-%016x%016x-%016x-%s", sc.TraceIDHigh, sc.TraceIDLow, sc.SpanID, flags))
+			req.Header.Set("tracestate", fmt.Sprintf("dd=s:%s", priority))
+		case "b3":
+			sampled := "0"
+			if sc.Priority.Keep() {
+				sampled = "1"
+			}
+			req.Header.Set("X-B3-TraceId", fmt.Sprintf("%016x%016x", sc.TraceIDHigh, sc.TraceIDLow))
+			req.Header.Set("X-B3-SpanId", fmt.Sprintf("%016x", sc.SpanID))
+			req.Header.Set("X-B3-Sampled", sampled)
+			req.Header.Set("b3", fmt.Sprintf("%016x%016x-%016x-%s", sc.TraceIDHigh, sc.TraceIDLow, sc.SpanID, sampled))
+		}
+	}
+}
+
+// extractW3CTraceContext parses the "traceparent" header per the W3C Trace
+// Context specification: version-traceid-parentid-flags.
+func extractW3CTraceContext(req *http.Request) (SpanContext, bool) {
+	header := req.Header.Get("traceparent")
+	if header == "" {
+		return SpanContext{}, false
+	}
+
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}, false
+	}
+
+	traceIDHigh, err := strconv.ParseUint(parts[1][:16], 16, 64)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	traceIDLow, err := strconv.ParseUint(parts[1][16:], 16, 64)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	priority := PriorityAutoDrop
+	if flags&0x1 == 1 {
+		priority = PriorityAutoKeep
+	}
+
+	return SpanContext{
+		TraceIDHigh: traceIDHigh,
+		TraceIDLow:  traceIDLow,
+		SpanID:      spanID,
+		Priority:    priority,
+	}, true
+}
+
+// extractB3 parses either the single "b3" header or the multi-header
+// "X-B3-*" form. B3 treats a missing sampled indicator as "undecided",
+// which callers of this plugin should treat the same as keep, so only an
+// explicit "0" drops the trace.
+func extractB3(req *http.Request) (SpanContext, bool) {
+	if single := req.Header.Get("b3"); single != "" {
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			return SpanContext{}, false
+		}
+		sc, ok := parseB3TraceAndSpan(parts[0], parts[1])
+		if !ok {
+			return SpanContext{}, false
+		}
+		sc.Priority = PriorityAutoKeep
+		if len(parts) >= 3 && parts[2] == "0" {
+			sc.Priority = PriorityAutoDrop
+		}
+		return sc, true
+	}
+
+	traceID := req.Header.Get("X-B3-TraceId")
+	spanID := req.Header.Get("X-B3-SpanId")
+	if traceID == "" || spanID == "" {
+		return SpanContext{}, false
+	}
+	sc, ok := parseB3TraceAndSpan(traceID, spanID)
+	if !ok {
+		return SpanContext{}, false
+	}
+	sc.Priority = PriorityAutoKeep
+	if req.Header.Get("X-B3-Sampled") == "0" {
+		sc.Priority = PriorityAutoDrop
+	}
+	return sc, true
+}
+
+// parseB3TraceAndSpan decodes B3's hex-encoded 64-bit or 128-bit trace ID
+// and 64-bit span ID.
+func parseB3TraceAndSpan(traceID, spanID string) (SpanContext, bool) {
+	var sc SpanContext
+
+	switch len(traceID) {
+	case 16:
+		low, err := strconv.ParseUint(traceID, 16, 64)
+		if err != nil {
+			return SpanContext{}, false
+		}
+		sc.TraceIDLow = low
+	case 32:
+		high, err := strconv.ParseUint(traceID[:16], 16, 64)
+		if err != nil {
+			return SpanContext{}, false
+		}
+		low, err := strconv.ParseUint(traceID[16:], 16, 64)
+		if err != nil {
+			return SpanContext{}, false
+		}
+		sc.TraceIDHigh = high
+		sc.TraceIDLow = low
+	default:
+		return SpanContext{}, false
+	}
+
+	id, err := strconv.ParseUint(spanID, 16, 64)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.SpanID = id
+	return sc, true
+}
+
+// extractDatadog parses Datadog's decimal "x-datadog-*" headers.
+func extractDatadog(req *http.Request) (SpanContext, bool) {
+	traceID := req.Header.Get("x-datadog-trace-id")
+	parentID := req.Header.Get("x-datadog-parent-id")
+	if traceID == "" || parentID == "" {
+		return SpanContext{}, false
+	}
+
+	low, err := strconv.ParseUint(traceID, 10, 64)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	spanID, err := strconv.ParseUint(parentID, 10, 64)
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	sc := SpanContext{TraceIDLow: low, SpanID: spanID, Priority: PriorityAutoKeep}
+	if priority := req.Header.Get("x-datadog-sampling-priority"); priority != "" {
+		if parsed, err := strconv.ParseInt(priority, 10, 8); err == nil {
+			sc.Priority = SamplingPriority(parsed)
+		}
+	}
+	return sc, true
+}
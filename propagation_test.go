@@ -0,0 +1,160 @@
+package traefik_datadog_tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractW3CTraceContext(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-000000000000000a000000000000000b-000000000000000c-01")
+
+	sc, ok := extractW3CTraceContext(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if sc.TraceIDHigh != 0xa || sc.TraceIDLow != 0xb || sc.SpanID != 0xc {
+		t.Fatalf("unexpected ids: %+v", sc)
+	}
+	if !sc.Priority.Keep() {
+		t.Fatalf("expected the sampled flag to be honored")
+	}
+}
+
+func TestExtractW3CTraceContextNotSampled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-000000000000000a000000000000000b-000000000000000c-00")
+
+	sc, ok := extractW3CTraceContext(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if sc.Priority.Keep() {
+		t.Fatalf("expected the unsampled flag to be honored")
+	}
+}
+
+func TestExtractW3CTraceContextMissing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := extractW3CTraceContext(req); ok {
+		t.Fatalf("expected no trace context without a traceparent header")
+	}
+}
+
+func TestExtractB3SingleHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("b3", "000000000000000a-000000000000000b-1")
+
+	sc, ok := extractB3(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if sc.TraceIDLow != 0xa || sc.SpanID != 0xb {
+		t.Fatalf("unexpected ids: %+v", sc)
+	}
+	if !sc.Priority.Keep() {
+		t.Fatalf("expected the sampled segment to be honored")
+	}
+}
+
+func TestExtractB3SingleHeaderUndecided(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("b3", "000000000000000a-000000000000000b")
+
+	sc, ok := extractB3(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if !sc.Priority.Keep() {
+		t.Fatalf("expected a missing sampled segment to default to keep")
+	}
+}
+
+func TestExtractB3MultiHeaderUndecided(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-B3-TraceId", "000000000000000a")
+	req.Header.Set("X-B3-SpanId", "000000000000000b")
+
+	sc, ok := extractB3(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if !sc.Priority.Keep() {
+		t.Fatalf("expected a missing X-B3-Sampled header to default to keep")
+	}
+}
+
+func TestExtractB3MultiHeaderNotSampled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-B3-TraceId", "000000000000000a")
+	req.Header.Set("X-B3-SpanId", "000000000000000b")
+	req.Header.Set("X-B3-Sampled", "0")
+
+	sc, ok := extractB3(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if sc.Priority.Keep() {
+		t.Fatalf("expected an explicit X-B3-Sampled: 0 to drop")
+	}
+}
+
+func TestExtractB3Missing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := extractB3(req); ok {
+		t.Fatalf("expected no trace context without any B3 headers")
+	}
+}
+
+func TestExtractDatadog(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-datadog-trace-id", "10")
+	req.Header.Set("x-datadog-parent-id", "11")
+	req.Header.Set("x-datadog-sampling-priority", "2")
+
+	sc, ok := extractDatadog(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if sc.TraceIDLow != 10 || sc.SpanID != 11 || sc.Priority != PriorityUserKeep {
+		t.Fatalf("unexpected span context: %+v", sc)
+	}
+}
+
+func TestExtractDatadogDefaultsToAutoKeep(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-datadog-trace-id", "10")
+	req.Header.Set("x-datadog-parent-id", "11")
+
+	sc, ok := extractDatadog(req)
+	if !ok {
+		t.Fatalf("expected a trace context to be extracted")
+	}
+	if sc.Priority != PriorityAutoKeep {
+		t.Fatalf("expected a missing priority header to default to auto-keep, got %v", sc.Priority)
+	}
+}
+
+func TestExtractDatadogMissing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := extractDatadog(req); ok {
+		t.Fatalf("expected no trace context without x-datadog-trace-id/parent-id")
+	}
+}
+
+func TestParseB3TraceAndSpan128Bit(t *testing.T) {
+	sc, ok := parseB3TraceAndSpan("000000000000000a000000000000000b", "000000000000000c")
+	if !ok {
+		t.Fatalf("expected a successful parse")
+	}
+	if sc.TraceIDHigh != 0xa || sc.TraceIDLow != 0xb || sc.SpanID != 0xc {
+		t.Fatalf("unexpected span context: %+v", sc)
+	}
+}
+
+func TestParseB3TraceAndSpanInvalidLength(t *testing.T) {
+	if _, ok := parseB3TraceAndSpan("abc", "000000000000000c"); ok {
+		t.Fatalf("expected an invalid length trace ID to fail to parse")
+	}
+}
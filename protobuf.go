@@ -0,0 +1,54 @@
+package traefik_datadog_tracing
+
+import "encoding/binary"
+
+// A minimal protobuf wire-format writer. As with the Jaeger Thrift writer,
+// Traefik plugins can only depend on the standard library, so rather than
+// vendoring the generated OTLP Go bindings this encodes just the messages
+// the otlp exporter needs directly against the wire format.
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+)
+
+func protoTag(fieldNumber int, wireType int) []byte {
+	return protoVarint(uint64(fieldNumber)<<3 | uint64(wireType))
+}
+
+func protoVarint(v uint64) []byte {
+	buf := make([]byte, 0, 10)
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+// appendVarintField appends a varint-typed field (used for enums and small
+// integers).
+func appendVarintField(buf []byte, fieldNumber int, v uint64) []byte {
+	buf = append(buf, protoTag(fieldNumber, protoWireVarint)...)
+	return append(buf, protoVarint(v)...)
+}
+
+// appendFixed64Field appends a fixed64-typed field (used for nanosecond
+// timestamps, matching OTLP's `fixed64` type for start/end time).
+func appendFixed64Field(buf []byte, fieldNumber int, v uint64) []byte {
+	buf = append(buf, protoTag(fieldNumber, protoWireFixed64)...)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// appendBytesField appends a length-delimited field: strings, bytes, and
+// embedded messages are all encoded the same way in protobuf's wire format.
+func appendBytesField(buf []byte, fieldNumber int, data []byte) []byte {
+	buf = append(buf, protoTag(fieldNumber, protoWireBytes)...)
+	buf = append(buf, protoVarint(uint64(len(data)))...)
+	return append(buf, data...)
+}
+
+func appendStringField(buf []byte, fieldNumber int, s string) []byte {
+	return appendBytesField(buf, fieldNumber, []byte(s))
+}
@@ -0,0 +1,72 @@
+package traefik_datadog_tracing
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// statusRecorder wraps an http.ResponseWriter to capture the status code and
+// byte count written by the downstream handler, which ServeHTTP otherwise
+// has no visibility into. It delegates the optional Flusher/Hijacker/Pusher
+// interfaces via type assertions so it doesn't strip capabilities (like
+// streaming or WebSocket upgrades) from handlers that rely on them.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+	wroteHeader  bool
+}
+
+// newStatusRecorder wraps rw, defaulting to 200 OK in case the handler never
+// calls WriteHeader explicitly.
+func newStatusRecorder(rw http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: rw, statusCode: http.StatusOK}
+}
+
+// WriteHeader records the status code of the first call and forwards it.
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes written, defaulting the status to 200
+// if the handler writes the body without calling WriteHeader first.
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytesWritten += int64(n)
+	return n, err
+}
+
+// Flush delegates to the underlying ResponseWriter's Flusher, if it has one.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack delegates to the underlying ResponseWriter's Hijacker, if it has
+// one.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Push delegates to the underlying ResponseWriter's Pusher, if it has one.
+func (r *statusRecorder) Push(target string, opts *http.PushOptions) error {
+	p, ok := r.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
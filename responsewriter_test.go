@@ -0,0 +1,69 @@
+package traefik_datadog_tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusRecorderDefaultsTo200(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	if rec.statusCode != http.StatusOK {
+		t.Fatalf("expected default status 200, got %d", rec.statusCode)
+	}
+}
+
+func TestStatusRecorderWriteHeader(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	rec.WriteHeader(http.StatusNotFound)
+	if rec.statusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.statusCode)
+	}
+}
+
+func TestStatusRecorderWriteHeaderOnlyRecordsFirstCall(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	rec.WriteHeader(http.StatusNotFound)
+	rec.WriteHeader(http.StatusInternalServerError)
+	if rec.statusCode != http.StatusNotFound {
+		t.Fatalf("expected the first WriteHeader call to stick, got %d", rec.statusCode)
+	}
+}
+
+func TestStatusRecorderWriteCountsBytes(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	n, err := rec.Write([]byte("hello"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 5 || rec.bytesWritten != 5 {
+		t.Fatalf("expected 5 bytes written, got n=%d bytesWritten=%d", n, rec.bytesWritten)
+	}
+
+	if _, err := rec.Write([]byte("world")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.bytesWritten != 10 {
+		t.Fatalf("expected bytesWritten to accumulate across writes, got %d", rec.bytesWritten)
+	}
+}
+
+func TestStatusRecorderHijackUnsupported(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	if _, _, err := rec.Hijack(); err == nil {
+		t.Fatalf("expected an error when the underlying ResponseWriter does not support hijacking")
+	}
+}
+
+func TestStatusRecorderPushUnsupported(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	if err := rec.Push("/", nil); err != http.ErrNotSupported {
+		t.Fatalf("expected http.ErrNotSupported, got %v", err)
+	}
+}
+
+func TestStatusRecorderFlush(t *testing.T) {
+	rec := newStatusRecorder(httptest.NewRecorder())
+	// httptest.ResponseRecorder implements http.Flusher; this should not panic.
+	rec.Flush()
+}
@@ -0,0 +1,137 @@
+package traefik_datadog_tracing
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SamplingPriority mirrors Datadog's priority sampling values so that
+// downstream services that only understand the Datadog headers still get a
+// meaningful decision.
+type SamplingPriority int8
+
+const (
+	PriorityUserDrop SamplingPriority = -1
+	PriorityAutoDrop SamplingPriority = 0
+	PriorityAutoKeep SamplingPriority = 1
+	PriorityUserKeep SamplingPriority = 2
+)
+
+// Keep reports whether a priority means the trace should be recorded.
+func (p SamplingPriority) Keep() bool {
+	return p > PriorityAutoDrop
+}
+
+// Sampler decides, for a given trace, whether it should be kept. It is
+// invoked once per trace at the root span so the decision is consistent for
+// every span in the trace.
+type Sampler interface {
+	Sample(traceID uint64) SamplingPriority
+}
+
+// AlwaysOn keeps every trace.
+type AlwaysOn struct{}
+
+func (AlwaysOn) Sample(uint64) SamplingPriority {
+	return PriorityAutoKeep
+}
+
+// Probabilistic keeps a fixed fraction of traces. Because the decision is a
+// pure function of the trace ID, every span in the trace (and every service
+// that recomputes it the same way) arrives at the same answer without
+// needing to share state.
+type Probabilistic struct {
+	Rate float64 // between 0 and 1
+}
+
+func (s Probabilistic) Sample(traceID uint64) SamplingPriority {
+	// Rate*MaxUint64 is not representable as a uint64 once Rate reaches 1,
+	// so that case is handled before the conversion.
+	if s.Rate >= 1 {
+		return PriorityAutoKeep
+	}
+	threshold := uint64(s.Rate * float64(math.MaxUint64))
+	if traceID < threshold {
+		return PriorityAutoKeep
+	}
+	return PriorityAutoDrop
+}
+
+// RateLimiting keeps at most PerSecond traces per second using a token
+// bucket refilled by elapsed wall-clock time.
+type RateLimiting struct {
+	PerSecond float64
+
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+}
+
+// newRateLimitingSampler creates a RateLimiting sampler with a full bucket.
+func newRateLimitingSampler(perSecond float64) *RateLimiting {
+	return &RateLimiting{PerSecond: perSecond, tokens: perSecond, lastRefill: time.Now()}
+}
+
+func (s *RateLimiting) Sample(uint64) SamplingPriority {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens = math.Min(s.PerSecond, s.tokens+now.Sub(s.lastRefill).Seconds()*s.PerSecond)
+	s.lastRefill = now
+
+	if s.tokens < 1 {
+		return PriorityAutoDrop
+	}
+	s.tokens--
+	return PriorityAutoKeep
+}
+
+// SamplerOverride applies a different sampling rate to requests matching a
+// URL path prefix or Host header, taking priority over the plugin's default
+// sampler.
+type SamplerOverride struct {
+	PathPrefix         string  `json:"pathPrefix,omitempty"`
+	Host               string  `json:"host,omitempty"`
+	SamplingRate       float64 `json:"samplingRate,omitempty"`
+	MaxTracesPerSecond float64 `json:"maxTracesPerSecond,omitempty"`
+}
+
+// routeSampler pairs a compiled SamplerOverride's matcher with its Sampler.
+type routeSampler struct {
+	pathPrefix string
+	host       string
+	sampler    Sampler
+}
+
+// newSampler picks a Sampler implementation from a sampling rate and/or a
+// rate limit, preferring the rate limit when both are set. A samplingRate of
+// 0 is a deliberate "keep nothing" and must not fall through to AlwaysOn.
+func newSampler(samplingRate, maxTracesPerSecond float64) Sampler {
+	switch {
+	case maxTracesPerSecond > 0:
+		return newRateLimitingSampler(maxTracesPerSecond)
+	case samplingRate >= 0 && samplingRate < 1:
+		return Probabilistic{Rate: samplingRate}
+	default:
+		return AlwaysOn{}
+	}
+}
+
+// samplerFor returns the first SamplerOverride matching req, or the
+// plugin's default sampler if none match.
+func (p *TracingPlugin) samplerFor(req *http.Request) Sampler {
+	for _, rs := range p.routeSamplers {
+		if rs.pathPrefix != "" && !strings.HasPrefix(req.URL.Path, rs.pathPrefix) {
+			continue
+		}
+		if rs.host != "" && req.Host != rs.host {
+			continue
+		}
+		return rs.sampler
+	}
+	return p.defaultSampler
+}
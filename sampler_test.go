@@ -0,0 +1,83 @@
+package traefik_datadog_tracing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlwaysOnSamples(t *testing.T) {
+	if !(AlwaysOn{}).Sample(0).Keep() {
+		t.Fatalf("expected AlwaysOn to keep every trace")
+	}
+}
+
+func TestProbabilisticFullRate(t *testing.T) {
+	s := Probabilistic{Rate: 1}
+	if !s.Sample(0).Keep() {
+		t.Fatalf("expected a rate of 1 to keep every trace")
+	}
+}
+
+func TestProbabilisticZeroRate(t *testing.T) {
+	s := Probabilistic{Rate: 0}
+	if s.Sample(0).Keep() {
+		t.Fatalf("expected a rate of 0 to drop every trace")
+	}
+}
+
+func TestRateLimitingSamplesUpToPerSecond(t *testing.T) {
+	s := newRateLimitingSampler(2)
+
+	if !s.Sample(0).Keep() {
+		t.Fatalf("expected the first trace to be kept")
+	}
+	if !s.Sample(0).Keep() {
+		t.Fatalf("expected the second trace to be kept")
+	}
+	if s.Sample(0).Keep() {
+		t.Fatalf("expected the third trace to exceed the bucket and be dropped")
+	}
+}
+
+func TestRateLimitingRefillsOverTime(t *testing.T) {
+	s := newRateLimitingSampler(1)
+	s.tokens = 0
+	s.lastRefill = time.Now().Add(-time.Second)
+
+	if !s.Sample(0).Keep() {
+		t.Fatalf("expected the bucket to have refilled after a second")
+	}
+}
+
+func TestNewSamplerPrefersRateLimit(t *testing.T) {
+	s := newSampler(1, 5)
+	if _, ok := s.(*RateLimiting); !ok {
+		t.Fatalf("expected a rate limit to take priority over a sampling rate, got %T", s)
+	}
+}
+
+func TestNewSamplerProbabilistic(t *testing.T) {
+	s := newSampler(0.5, 0)
+	p, ok := s.(Probabilistic)
+	if !ok || p.Rate != 0.5 {
+		t.Fatalf("expected Probabilistic{Rate: 0.5}, got %#v", s)
+	}
+}
+
+func TestNewSamplerZeroRateKeepsNothing(t *testing.T) {
+	s := newSampler(0, 0)
+	p, ok := s.(Probabilistic)
+	if !ok || p.Rate != 0 {
+		t.Fatalf("expected a samplingRate of 0 to produce Probabilistic{Rate: 0}, got %#v", s)
+	}
+	if s.Sample(0).Keep() {
+		t.Fatalf("expected a samplingRate of 0 to never keep a trace")
+	}
+}
+
+func TestNewSamplerDefaultsToAlwaysOn(t *testing.T) {
+	s := newSampler(1, 0)
+	if _, ok := s.(AlwaysOn); !ok {
+		t.Fatalf("expected a samplingRate of 1 to produce AlwaysOn, got %T", s)
+	}
+}
@@ -0,0 +1,19 @@
+package traefik_datadog_tracing
+
+import "time"
+
+// Span is the internal, backend-neutral representation of a finished span.
+// It is produced by ServeHTTP and handed off to a spanWriter for export.
+type Span struct {
+	TraceIDHigh uint64
+	TraceIDLow  uint64
+	SpanID      uint64
+	ParentID    uint64
+	Name        string
+	Resource    string
+	Service     string
+	Start       time.Time
+	Duration    time.Duration
+	Meta        map[string]string
+	Error       bool
+}
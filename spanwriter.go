@@ -0,0 +1,134 @@
+package traefik_datadog_tracing
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spanWriter buffers finished spans and flushes them to the configured
+// Exporter on a background goroutine, so that ServeHTTP never blocks on the
+// network.
+type spanWriter struct {
+	exporter       Exporter
+	queue          chan Span
+	flushBatchSize int
+	flushInterval  time.Duration
+
+	dropped uint64 // atomic; spans discarded because the queue was full
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// newSpanWriter creates a spanWriter. Call start to begin the background
+// flush loop.
+func newSpanWriter(exporter Exporter, maxQueueSize, flushBatchSize int, flushInterval time.Duration) *spanWriter {
+	return &spanWriter{
+		exporter:       exporter,
+		queue:          make(chan Span, maxQueueSize),
+		flushBatchSize: flushBatchSize,
+		flushInterval:  flushInterval,
+		done:           make(chan struct{}),
+	}
+}
+
+// start launches the background flush loop. It must only be called once.
+func (w *spanWriter) start() {
+	w.wg.Add(1)
+	go w.run()
+}
+
+// enqueue submits a finished span for asynchronous export. If the queue is
+// full the span is dropped rather than blocking the caller, and the drop is
+// counted for periodic logging.
+func (w *spanWriter) enqueue(span Span) {
+	select {
+	case w.queue <- span:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+}
+
+// run is the background flush loop: it batches queued spans and flushes
+// whenever the batch reaches flushBatchSize or flushInterval elapses.
+func (w *spanWriter) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	dropLogTicker := time.NewTicker(30 * time.Second)
+	defer dropLogTicker.Stop()
+
+	batch := make([]Span, 0, w.flushBatchSize)
+
+	for {
+		select {
+		case span := <-w.queue:
+			batch = append(batch, span)
+			if len(batch) >= w.flushBatchSize {
+				batch = w.flush(batch)
+			}
+
+		case <-ticker.C:
+			batch = w.flush(batch)
+
+		case <-dropLogTicker.C:
+			if dropped := atomic.SwapUint64(&w.dropped, 0); dropped > 0 {
+				log.Printf("spanWriter: dropped %d spans due to a full queue", dropped)
+			}
+
+		case <-w.done:
+			// Drain whatever is already queued, then flush and exit.
+			for {
+				select {
+				case span := <-w.queue:
+					batch = append(batch, span)
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush hands batch to the exporter and returns it truncated to zero length,
+// so its backing array is reused for the next batch.
+func (w *spanWriter) flush(batch []Span) []Span {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := w.exporter.ExportSpans(ctx, batch); err != nil {
+		log.Printf("spanWriter: error exporting %d spans: %v", len(batch), err)
+	}
+
+	return batch[:0]
+}
+
+// shutdown stops the flush loop after draining and flushing any spans still
+// queued, or returns ctx.Err() if it doesn't finish in time.
+func (w *spanWriter) shutdown(ctx context.Context) error {
+	w.closeOnce.Do(func() { close(w.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
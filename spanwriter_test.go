@@ -0,0 +1,132 @@
+package traefik_datadog_tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExporter records every batch handed to ExportSpans so tests can assert
+// on flush timing and batch contents without any real network I/O.
+type fakeExporter struct {
+	mu      sync.Mutex
+	batches [][]Span
+}
+
+func (e *fakeExporter) ExportSpans(ctx context.Context, spans []Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	batch := make([]Span, len(spans))
+	copy(batch, spans)
+	e.batches = append(e.batches, batch)
+	return nil
+}
+
+func (e *fakeExporter) spanCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, b := range e.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func (e *fakeExporter) batchCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.batches)
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("condition not met within %s", timeout)
+}
+
+func TestSpanWriterFlushesOnBatchSize(t *testing.T) {
+	exporter := &fakeExporter{}
+	w := newSpanWriter(exporter, 100, 2, time.Hour)
+	w.start()
+	defer w.shutdown(context.Background())
+
+	w.enqueue(Span{Name: "a"})
+	w.enqueue(Span{Name: "b"})
+
+	waitFor(t, time.Second, func() bool { return exporter.spanCount() == 2 })
+}
+
+func TestSpanWriterFlushesOnInterval(t *testing.T) {
+	exporter := &fakeExporter{}
+	w := newSpanWriter(exporter, 100, 100, 10*time.Millisecond)
+	w.start()
+	defer w.shutdown(context.Background())
+
+	w.enqueue(Span{Name: "a"})
+
+	waitFor(t, time.Second, func() bool { return exporter.spanCount() == 1 })
+}
+
+func TestSpanWriterDropsOnFullQueue(t *testing.T) {
+	exporter := &fakeExporter{}
+	w := newSpanWriter(exporter, 1, 100, time.Hour)
+	// Deliberately don't start the flush loop, so the queue fills up.
+	w.enqueue(Span{Name: "a"})
+	w.enqueue(Span{Name: "b"})
+
+	if dropped := w.dropped; dropped != 1 {
+		t.Fatalf("expected 1 dropped span, got %d", dropped)
+	}
+}
+
+func TestSpanWriterShutdownDrainsAndFlushes(t *testing.T) {
+	exporter := &fakeExporter{}
+	w := newSpanWriter(exporter, 100, 100, time.Hour)
+	w.start()
+
+	w.enqueue(Span{Name: "a"})
+	w.enqueue(Span{Name: "b"})
+
+	if err := w.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exporter.spanCount() != 2 {
+		t.Fatalf("expected shutdown to flush queued spans, got %d", exporter.spanCount())
+	}
+}
+
+func TestSpanWriterShutdownIsIdempotent(t *testing.T) {
+	exporter := &fakeExporter{}
+	w := newSpanWriter(exporter, 100, 100, time.Hour)
+	w.start()
+
+	if err := w.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error on first shutdown: %v", err)
+	}
+	if err := w.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error on second shutdown: %v", err)
+	}
+}
+
+func TestSpanWriterShutdownTimesOut(t *testing.T) {
+	exporter := &fakeExporter{}
+	w := newSpanWriter(exporter, 100, 100, time.Hour)
+	// Never started: the flush loop never runs, so wg.Wait blocks forever
+	// and shutdown must respect the context deadline instead of hanging.
+	w.wg.Add(1)
+	defer w.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := w.shutdown(ctx); err == nil {
+		t.Fatalf("expected shutdown to time out")
+	}
+}
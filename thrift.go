@@ -0,0 +1,97 @@
+package traefik_datadog_tracing
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// A minimal TBinaryProtocol struct writer. Traefik plugins can only depend
+// on the standard library, so rather than vendoring Apache Thrift this
+// implements just enough of the binary protocol to encode the handful of
+// Jaeger Thrift structs the jaeger exporter needs.
+const (
+	thriftTypeStop   = 0
+	thriftTypeBool   = 2
+	thriftTypeDouble = 4
+	thriftTypeI32    = 8
+	thriftTypeI64    = 10
+	thriftTypeString = 11
+	thriftTypeStruct = 12
+	thriftTypeList   = 15
+)
+
+type thriftWriter struct {
+	buf []byte
+}
+
+func (w *thriftWriter) fieldHeader(thriftType byte, fieldID int16) {
+	w.buf = append(w.buf, thriftType)
+	var id [2]byte
+	binary.BigEndian.PutUint16(id[:], uint16(fieldID))
+	w.buf = append(w.buf, id[:]...)
+}
+
+func (w *thriftWriter) stop() {
+	w.buf = append(w.buf, thriftTypeStop)
+}
+
+func (w *thriftWriter) writeString(fieldID int16, s string) {
+	w.fieldHeader(thriftTypeString, fieldID)
+	w.writeRawString(s)
+}
+
+func (w *thriftWriter) writeRawString(s string) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(s)))
+	w.buf = append(w.buf, length[:]...)
+	w.buf = append(w.buf, s...)
+}
+
+func (w *thriftWriter) writeI32(fieldID int16, v int32) {
+	w.fieldHeader(thriftTypeI32, fieldID)
+	w.writeRawI32(v)
+}
+
+func (w *thriftWriter) writeRawI32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *thriftWriter) writeI64(fieldID int16, v int64) {
+	w.fieldHeader(thriftTypeI64, fieldID)
+	w.writeRawI64(v)
+}
+
+func (w *thriftWriter) writeRawI64(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *thriftWriter) writeDouble(fieldID int16, v float64) {
+	w.fieldHeader(thriftTypeDouble, fieldID)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+// writeStruct writes a nested struct's already-encoded body (including its
+// trailing STOP byte) as the value of fieldID.
+func (w *thriftWriter) writeStruct(fieldID int16, body []byte) {
+	w.fieldHeader(thriftTypeStruct, fieldID)
+	w.buf = append(w.buf, body...)
+}
+
+// writeList writes fieldID as a list of elemType with the given
+// already-encoded elements concatenated together.
+func (w *thriftWriter) writeList(fieldID int16, elemType byte, count int, elements []byte) {
+	w.fieldHeader(thriftTypeList, fieldID)
+	w.buf = append(w.buf, elemType)
+	w.writeRawI32(int32(count))
+	w.buf = append(w.buf, elements...)
+}
+
+func (w *thriftWriter) bytes() []byte {
+	return w.buf
+}